@@ -0,0 +1,132 @@
+package graphqlgo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// RunBatch sends multiple operations as a single GraphQL batch request --
+// the array-form body ([{query:...},{query:...}]) supported by Apollo
+// Server and other implementations -- mapping each response back to the
+// resps entry at the same index. resps must be the same length as reqs;
+// a nil entry skips response parsing for that operation.
+//
+// If WithMaxBatchSize was used, a batch larger than that is split into
+// several HTTP calls. RunBatch does not go through WithMiddleware or
+// WithRetry: those apply to single operations run with Run.
+func (c *Client) RunBatch(ctx context.Context, reqs []*Request, resps []interface{}) ([]RunResult, error) {
+	if len(resps) != len(reqs) {
+		return nil, fmt.Errorf("graphqlgo: RunBatch needs one response slot per request, got %d requests and %d responses", len(reqs), len(resps))
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	chunkSize := c.maxBatchSize
+	if chunkSize <= 0 {
+		chunkSize = len(reqs)
+	}
+
+	results := make([]RunResult, len(reqs))
+
+	for start := 0; start < len(reqs); start += chunkSize {
+		end := start + chunkSize
+		if end > len(reqs) {
+			end = len(reqs)
+		}
+
+		if err := c.runBatchChunk(ctx, reqs[start:end], resps[start:end], results[start:end]); err != nil {
+			return results, err
+		}
+	}
+
+	return results, nil
+}
+
+// runBatchChunk sends one HTTP call for a slice of operations small
+// enough to fit in a single batch, filling in the corresponding slice of
+// results.
+func (c *Client) runBatchChunk(ctx context.Context, reqs []*Request, resps []interface{}, results []RunResult) error {
+	bodies := make([]RequestBody, len(reqs))
+	for i, req := range reqs {
+		bodies[i] = RequestBody{
+			Query:         req.q,
+			Variables:     req.vars,
+			OperationName: req.opName,
+		}
+	}
+
+	inspect := InspectData{"ReqBody": bodies}
+
+	var reqBuf bytes.Buffer
+	if err := json.NewEncoder(&reqBuf).Encode(bodies); err != nil {
+		return errors.Wrap(err, "encode batch body")
+	}
+
+	r, err := http.NewRequest(http.MethodPost, c.Endpoint, &reqBuf)
+	if err != nil {
+		return err
+	}
+	r.Close = c.closeReq
+
+	for key, values := range c.Header {
+		for _, value := range values {
+			r.Header.Add(key, value)
+		}
+	}
+	inspect["ReqHeaders"] = r.Header
+
+	r = r.WithContext(ctx)
+	res, err := c.httpClient.Do(r)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	inspect["ResStatusCode"] = res.StatusCode
+	inspect["ResHeaders"] = res.Header
+	inspect["ResCookies"] = res.Cookies()
+	inspect["ResContentLength"] = res.ContentLength
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP Error %v: graphql server returned a non-200 status code", res.StatusCode)
+	}
+
+	var resBuf bytes.Buffer
+	if _, err := io.Copy(&resBuf, res.Body); err != nil {
+		return errors.Wrap(err, "reading body")
+	}
+	inspect["ResBody"] = resBuf.String()
+
+	var grs []GraphQLResponse
+	if err := json.NewDecoder(&resBuf).Decode(&grs); err != nil {
+		return errors.Wrap(err, "decoding batch response")
+	}
+	if len(grs) != len(reqs) {
+		return fmt.Errorf("graphqlgo: batch response has %d entries, expected %d", len(grs), len(reqs))
+	}
+
+	for i, gr := range grs {
+		results[i] = RunResult{
+			InspectRun:  inspect,
+			InspectRuns: []InspectData{inspect},
+			Errors:      gr.Errors,
+		}
+		if resps[i] != nil && gr.Data != nil {
+			if err := decodeInto(gr.Data, resps[i]); err != nil {
+				return errors.Wrapf(err, "decoding response %d", i)
+			}
+		}
+	}
+
+	return nil
+}
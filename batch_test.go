@@ -0,0 +1,97 @@
+package graphqlgo
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunBatch(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal("ReadAll should not return error:", err)
+		}
+
+		wantBody := `[{"query":"query a {}","variables":null,"operationName":null},{"query":"query b {}","variables":null,"operationName":null}]`
+		if strings.TrimSpace(string(b)) != wantBody {
+			t.Errorf("body = %q, want %q", string(b), wantBody)
+		}
+
+		io.WriteString(w, `[{"data":{"a":1}},{"data":{"b":2}}]`)
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	var respA, respB map[string]int
+	results, err := client.RunBatch(ctx,
+		[]*Request{{q: "query a {}"}, {q: "query b {}"}},
+		[]interface{}{&respA, &respB},
+	)
+	if err != nil {
+		t.Fatalf("RunBatch should not return error: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected a single HTTP call, got %d", calls)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if respA["a"] != 1 {
+		t.Errorf("unexpected respA: %#v", respA)
+	}
+	if respB["b"] != 2 {
+		t.Errorf("unexpected respB: %#v", respB)
+	}
+}
+
+func TestRunBatchSplitsOnMaxBatchSize(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal("ReadAll should not return error:", err)
+		}
+		var ops []RequestBody
+		if err := json.Unmarshal(b, &ops); err != nil {
+			t.Fatalf("unmarshal batch body: %v", err)
+		}
+		if len(ops) != 1 {
+			t.Errorf("expected batches of 1, got %d", len(ops))
+		}
+		io.WriteString(w, `[{"data":{"ok":true}}]`)
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL, WithMaxBatchSize(1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	results, err := client.RunBatch(ctx,
+		[]*Request{{q: "query a {}"}, {q: "query b {}"}},
+		[]interface{}{nil, nil},
+	)
+	if err != nil {
+		t.Fatalf("RunBatch should not return error: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 HTTP calls with WithMaxBatchSize(1), got %d", calls)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+}
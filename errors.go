@@ -0,0 +1,97 @@
+package graphqlgo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Sentinel errors for the extensions.code values servers commonly use.
+// GraphQLError.Is matches these (and any sentinel registered with
+// WithErrorCodes) against a GraphQLError's Code, so that
+// errors.Is(err, ErrRateLimited) works against the ErrorList returned by
+// Run.
+var (
+	ErrUnauthenticated = errors.New("graphqlgo: unauthenticated")
+	ErrForbidden       = errors.New("graphqlgo: forbidden")
+	ErrRateLimited     = errors.New("graphqlgo: rate limited")
+	ErrValidation      = errors.New("graphqlgo: validation failed")
+)
+
+// defaultErrorCodes maps the extensions.code values servers commonly use
+// to a package sentinel. WithErrorCodes lets callers override or extend
+// this per Client.
+var defaultErrorCodes = map[string]error{
+	"UNAUTHENTICATED":           ErrUnauthenticated,
+	"FORBIDDEN":                 ErrForbidden,
+	"RATE_LIMITED":              ErrRateLimited,
+	"GRAPHQL_VALIDATION_FAILED": ErrValidation,
+	"BAD_USER_INPUT":            ErrValidation,
+}
+
+// Code returns this error's extensions.code, or "" if it has none.
+func (e *GraphQLError) Code() string {
+	code, _ := e.Extensions["code"].(string)
+	return code
+}
+
+// Is reports whether target is the sentinel error registered for this
+// error's Code -- first via the Client's WithErrorCodes, falling back to
+// the package defaults -- so that errors.Is(err, ErrRateLimited) and
+// errors.Is(err, myapp.ErrNotFound) work against an ErrorList returned by
+// Run.
+func (e *GraphQLError) Is(target error) bool {
+	code := e.Code()
+	if code == "" {
+		return false
+	}
+	if sentinel, ok := e.codes[code]; ok {
+		return sentinel == target
+	}
+	if sentinel, ok := defaultErrorCodes[code]; ok {
+		return sentinel == target
+	}
+	return false
+}
+
+// ErrorList is the GraphQL errors returned alongside a response, usable
+// directly as an error so callers can't forget to check it. errors.Is and
+// errors.As walk each entry via Unwrap.
+type ErrorList []*GraphQLError
+
+// Error satisfies the error interface.
+func (list ErrorList) Error() string {
+	switch len(list) {
+	case 0:
+		return "graphqlgo: empty error list"
+	case 1:
+		return list[0].Error()
+	default:
+		msgs := make([]string, len(list))
+		for i, e := range list {
+			msgs[i] = e.Error()
+		}
+		return fmt.Sprintf("%d graphql errors: %s", len(list), strings.Join(msgs, "; "))
+	}
+}
+
+// Unwrap exposes each error in list to errors.Is and errors.As (requires
+// Go 1.20+, which added support for Unwrap() []error).
+func (list ErrorList) Unwrap() []error {
+	errs := make([]error, len(list))
+	for i, e := range list {
+		errs[i] = e
+	}
+	return errs
+}
+
+// HasCode reports whether any error in list has the given extensions.code.
+func (list ErrorList) HasCode(code string) bool {
+	for _, e := range list {
+		if e.Code() == code {
+			return true
+		}
+	}
+	return false
+}
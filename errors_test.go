@@ -0,0 +1,116 @@
+package graphqlgo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRunReturnsErrorList(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{
+			"data": null,
+			"errors": [
+				{"message": "nope", "extensions": {"code": "FORBIDDEN"}}
+			]
+		}`)
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	_, err := client.Run(ctx, &Request{q: "query {}"}, nil)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	var list ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("expected err to be an ErrorList, got %T: %v", err, err)
+	}
+	if !list.HasCode("FORBIDDEN") {
+		t.Errorf("expected ErrorList to HasCode(%q)", "FORBIDDEN")
+	}
+	if !errors.Is(err, ErrForbidden) {
+		t.Error("expected errors.Is(err, ErrForbidden) to be true")
+	}
+	if errors.Is(err, ErrRateLimited) {
+		t.Error("expected errors.Is(err, ErrRateLimited) to be false")
+	}
+}
+
+func TestRunNilErrorOnSuccess(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"data": {"ok": true}}`)
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	var respData map[string]bool
+	_, err := client.Run(ctx, &Request{q: "query {}"}, &respData)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !respData["ok"] {
+		t.Errorf("unexpected response data: %#v", respData)
+	}
+}
+
+func TestRunErrorListCustomCode(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{
+			"data": null,
+			"errors": [
+				{"message": "no such widget", "extensions": {"code": "NOT_FOUND"}}
+			]
+		}`)
+	}))
+	defer srv.Close()
+
+	ErrNotFound := errors.New("myapp: not found")
+	client := NewClient(srv.URL, WithErrorCodes(map[string]error{"NOT_FOUND": ErrNotFound}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	_, err := client.Run(ctx, &Request{q: "query {}"}, nil)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected errors.Is(err, ErrNotFound) to be true, err: %v", err)
+	}
+}
+
+func TestRunRawStillReturnsRawErrorSlice(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{
+			"data": null,
+			"errors": [
+				{"message": "oops", "extensions": {"code": "FORBIDDEN"}}
+			]
+		}`)
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	_, gqerr, err := client.RunRaw(ctx, &Request{q: "query {}"}, nil)
+	if err != nil {
+		t.Fatalf("RunRaw should not return a transport error: %v", err)
+	}
+	if len(gqerr) != 1 || gqerr[0].Code() != "FORBIDDEN" {
+		t.Errorf("unexpected gqerr: %#v", gqerr)
+	}
+}
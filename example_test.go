@@ -35,20 +35,19 @@ func ExampleClient_Run() {
 	}
 	// note: to get map[string]interface{} use var respData interface{}
 
-	gqlerr, err := client.Run(ctx, req, &respData)
+	// Run returns a single error: nil, a transport error, or an
+	// ErrorList -- use errors.Is/errors.As against it to tell them apart.
+	_, err := client.Run(ctx, req, &respData)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	fmt.Printf("DATA\n%v\n", respData)
-	fmt.Println("--------------")
-	fmt.Printf("ERRORS\n%v\n", gqlerr)
-	// you can inspect request and response
-	// fmt.Println(prettyPrint(client.InspectRun))
+	// you can inspect request/response diagnostics, or get the raw
+	// GraphQLError slice, via RunRaw:
+	// result, gqlerr, _ := client.RunRaw(ctx, req, &respData)
+	// fmt.Println(prettyPrint(result.InspectRun))
 	// Output:
 	// DATA
 	// {{AF Africa}}
-	// --------------
-	// ERRORS
-	// []
 }
@@ -5,13 +5,12 @@
 // * to support existing standards
 // * to allow maximum request & response debugging
 //
-// The graphqlgo.Client is not concurrent safe and not intended to be.
-// If you need to make conccurrent requests, instantiate separate clients for them.
+// The graphqlgo.Client is concurrent safe: it holds no mutable per-call
+// state, so a single Client can be shared across goroutines.
 //
-// The Client keeps it's state (as request headers, data sent, received headers etc.).
-// The request and response it can be inspected after the request.
-// Every client.Run resets this state therefore you can use the client for multiple
-// non-concurrent requests.
+// Request and response diagnostics (headers, data sent, received headers
+// etc.) are returned per call as a RunResult alongside the response, rather
+// than kept on the Client -- see Run.
 //
 // Options for Client
 //
@@ -50,20 +49,39 @@
 // 	 )
 //
 // See example.
+//
+// Subscriptions
+//
+// To stream subscription results over a websocket (graphql-ws or
+// graphql-transport-ws), use Client.Subscribe. See subscription.go.
+//
+// File uploads
+//
+// To send files per the GraphQL multipart request spec, attach them with
+// the WithFiles request option. See upload.go.
+//
+// Typed errors
+//
+// Run returns the server's GraphQL errors as a single ErrorList error
+// rather than a separate slice, so they can't be left unchecked. Use
+// errors.Is/errors.As against ErrUnauthenticated, ErrForbidden,
+// ErrRateLimited, ErrValidation, or a sentinel registered with
+// WithErrorCodes, to tell them apart. See errors.go.
 package graphqlgo
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 
+	"github.com/gorilla/websocket"
 	"github.com/pkg/errors"
 )
 
-// Client is a client for interacting with a GraphQL API.
+// Client is a client for interacting with a GraphQL API. A Client is
+// concurrent safe: it holds no mutable per-call state. Diagnostics that
+// used to live on Client (InspectRun) are now returned per call as part
+// of RunResult -- see Run and RunBatch.
 type Client struct {
 	Endpoint   string
 	httpClient *http.Client
@@ -77,14 +95,57 @@ type Client struct {
 	// closeReq will close the request body immediately allowing for reuse of http client
 	closeReq bool
 
-	// Inspect contains info about the Req and Res of the last Run
-	// is resets on the beginning of every client.Run()
-	InspectRun map[string]interface{}
+	// retryPolicy configures automatic retries in Run. Nil means no
+	// retrying: a single attempt is made.
+	retryPolicy *RetryPolicy
+
+	// middlewares wrap the core HTTP transport; see WithMiddleware.
+	middlewares []Middleware
+
+	// maxBatchSize caps how many operations RunBatch sends per HTTP call.
+	// 0 means no cap: the whole batch is sent in one call.
+	maxBatchSize int
+
+	// wsDialer is used to open the websocket connection for Subscribe.
+	// Defaults to websocket.DefaultDialer.
+	wsDialer *websocket.Dialer
+
+	// initPayload is sent as the payload of the connection_init message
+	// when a subscription is opened.
+	initPayload InitPayload
+
+	// subprotocol selects which graphql-ws family subprotocol Subscribe
+	// speaks. Defaults to SubprotocolGraphQLWS.
+	subprotocol Subprotocol
+
+	// errorCodes maps extensions.code to a sentinel error, consulted by
+	// GraphQLError.Is in addition to the package's default codes. See
+	// WithErrorCodes.
+	errorCodes map[string]error
 }
 
 // InspectData provides info about request and response
 type InspectData map[string]interface{}
 
+// RunResult carries the per-call diagnostic data that used to live on
+// Client (InspectRun), returned alongside each Run/RunBatch call instead
+// so a Client has no mutable state and can be shared across goroutines.
+type RunResult struct {
+	// InspectRun is the last attempt's InspectData.
+	InspectRun InspectData
+
+	// InspectRuns holds one InspectData per attempt, in order. It has a
+	// single entry unless a RetryPolicy is set with WithRetry and the
+	// request was retried.
+	InspectRuns []InspectData
+
+	// Errors holds the GraphQL errors for this result. RunRaw also returns
+	// them as its own []GraphQLError (and Run as an ErrorList); this
+	// field exists so RunBatch, which returns one RunResult per
+	// operation, has somewhere to put them.
+	Errors []GraphQLError
+}
+
 // type InspectData struct {
 // 	ReqHeaders       http.Header
 // 	ReqBody          *RequestBody
@@ -124,91 +185,122 @@ type RequestBody struct {
 }
 
 // Run executes the query and unmarshals the response from the data field
-// into the response object.
-// Pass in a nil response object to skip response parsing.
-func (c *Client) Run(ctx context.Context, req *Request, resp interface{}) ([]GraphQLError, error) {
-	select {
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	default:
-	}
-
-	// here should be done some releasing/resetting the logging headers etc..
-	c.InspectRun = InspectData{}
-
-	var requestBody bytes.Buffer
-	requestBodyObj := RequestBody{
-		Query:         req.q,
-		Variables:     req.vars,
-		OperationName: req.opName,
-	}
-
-	// adding for possibility to inspect
-	c.InspectRun["ReqBody"] = &requestBodyObj
-
-	if err := json.NewEncoder(&requestBody).Encode(requestBodyObj); err != nil {
-		return nil, errors.Wrap(err, "encode body")
-	}
-
-	r, err := http.NewRequest(http.MethodPost, c.Endpoint, &requestBody)
+// into the response object. Pass in a nil response object to skip
+// response parsing.
+//
+// Run returns a single error: nil on success, the transport error if the
+// call failed outright, or an ErrorList if the server replied with
+// GraphQL errors. Use errors.Is/errors.As against ErrUnauthenticated,
+// ErrForbidden, ErrRateLimited, ErrValidation, or a sentinel registered
+// with WithErrorCodes, to inspect which. The returned RunResult carries
+// the per-attempt diagnostics; RunRaw returns the same RunResult with the
+// raw GraphQLError slice as a separate value.
+func (c *Client) Run(ctx context.Context, req *Request, resp interface{}) (RunResult, error) {
+	result, gqlErrs, err := c.RunRaw(ctx, req, resp)
 	if err != nil {
-		return nil, err
+		return result, err
 	}
-	r.Close = c.closeReq
-
-	// Adds headers defined on the client.
-	for key, values := range c.Header {
-		for _, value := range values {
-			r.Header.Add(key, value)
-		}
+	if len(gqlErrs) == 0 {
+		return result, nil
 	}
 
-	// Adds headers defined on the current request.
-	for key, values := range req.Header {
-		for _, value := range values {
-			r.Header.Add(key, value)
-		}
+	list := make(ErrorList, len(gqlErrs))
+	for i := range gqlErrs {
+		e := gqlErrs[i]
+		e.codes = c.errorCodes
+		list[i] = &e
 	}
-	c.InspectRun["ReqHeaders"] = r.Header
+	result.Errors = gqlErrs
+	return result, list
+}
 
-	r = r.WithContext(ctx)
-	res, err := c.httpClient.Do(r)
-	if err != nil {
-		return nil, err
+// RunRaw executes the query and unmarshals the response from the data
+// field into the response object, same as Run. Pass in a nil response
+// object to skip response parsing.
+//
+// RunRaw holds no state on c: everything about this call -- the request/
+// response diagnostics Client used to keep on InspectRun -- comes back in
+// the returned RunResult, so a single Client can be shared across
+// goroutines.
+//
+// Each attempt runs through the chain built from any middleware added with
+// WithMiddleware, innermost being the core HTTP transport. If a
+// RetryPolicy has been set with WithRetry, a failed attempt that the
+// policy considers retriable (network errors, retriable status codes or
+// GraphQL error codes by default) is retried with backoff, rewinding the
+// request body between attempts. RunResult.InspectRuns records one
+// InspectData per attempt; RunResult.InspectRun is always the last
+// attempt's InspectData.
+//
+// Requests with files attached via WithFiles are never retried, even with
+// a RetryPolicy set: Upload.Reader is consumed once per attempt and, being
+// an arbitrary io.Reader, can't generally be rewound without buffering the
+// file contents in memory. Such a request always runs exactly one attempt.
+func (c *Client) RunRaw(ctx context.Context, req *Request, resp interface{}) (RunResult, []GraphQLError, error) {
+	select {
+	case <-ctx.Done():
+		return RunResult{}, nil, ctx.Err()
+	default:
 	}
-	defer res.Body.Close()
 
-	// capture for inspection
-	c.InspectRun["ResStatusCode"] = res.StatusCode
-	c.InspectRun["ResHeaders"] = res.Header
-	c.InspectRun["ResCookies"] = res.Cookies() // should return func to be consistent with http?
-	c.InspectRun["ResContentLength"] = res.ContentLength
-
-	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP Error %v: graphql server returned a non-200 status code", res.StatusCode)
+	policy := c.retryPolicy
+	if policy == nil {
+		policy = &RetryPolicy{MaxAttempts: 1}
 	}
 
-	var buf bytes.Buffer
-	if _, err := io.Copy(&buf, res.Body); err != nil {
-		return nil, errors.Wrap(err, "reading body")
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts < 1 || len(req.files) > 0 {
+		maxAttempts = 1
 	}
 
-	// capture for inspection
-	c.InspectRun["ResBody"] = buf.String()
-
-	var gr GraphQLResponse
+	rt := c.chain()
+
+	var result RunResult
+	var gqlErrs []GraphQLError
+	var runErr error
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		inspect := InspectData{"Attempt": attempt}
+		result.InspectRun = inspect
+		result.InspectRuns = append(result.InspectRuns, inspect)
+
+		gr, res, rtErr := rt(withInspect(ctx, inspect), req)
+		if rtErr != nil {
+			runErr = rtErr
+			if attempt < maxAttempts && policy.retriable(res, nil, rtErr) {
+				delay := policy.backoff(attempt, 0)
+				if res != nil {
+					delay = policy.backoff(attempt, parseRetryAfter(res.Header))
+				}
+				if sleepErr := sleep(ctx, delay); sleepErr != nil {
+					return result, nil, sleepErr
+				}
+				continue
+			}
+			return result, nil, runErr
+		}
 
-	// inject own type
-	gr.Data = resp
+		if len(gr.Errors) > 0 {
+			gqlErrs = gr.Errors
+			if attempt < maxAttempts && policy.retriable(res, gqlErrs, nil) {
+				if sleepErr := sleep(ctx, policy.backoff(attempt, 0)); sleepErr != nil {
+					return result, nil, sleepErr
+				}
+				continue
+			}
+		}
 
-	if err := json.NewDecoder(&buf).Decode(&gr); err != nil {
-		return nil, errors.Wrap(err, "decoding response")
+		if resp != nil && gr.Data != nil {
+			if err := decodeInto(gr.Data, resp); err != nil {
+				return result, nil, errors.Wrap(err, "decoding response")
+			}
+		}
+		result.Errors = gqlErrs
+		return result, gqlErrs, nil
 	}
 
-	if len(gr.Errors) > 0 {
-		return gr.Errors, nil
-	}
-	return nil, nil
+	result.Errors = gqlErrs
+	return result, gqlErrs, runErr
 }
 
 // Vars sets a variables for the request
@@ -253,6 +345,26 @@ func ImmediatelyCloseReqBody() ClientOption {
 	}
 }
 
+// WithMaxBatchSize caps how many operations RunBatch sends in a single
+// HTTP POST, splitting a larger batch into multiple calls. 0 (the
+// default) sends the whole batch in one call.
+func WithMaxBatchSize(n int) ClientOption {
+	return func(client *Client) {
+		client.maxBatchSize = n
+	}
+}
+
+// WithErrorCodes registers a mapping from a GraphQL error's
+// extensions.code to a sentinel error, so that errors.Is(err,
+// myapp.ErrNotFound) works against the ErrorList returned by Run. Entries
+// here take precedence over the package's own defaults (ErrUnauthenticated,
+// ErrForbidden, ErrRateLimited, ErrValidation).
+func WithErrorCodes(codes map[string]error) ClientOption {
+	return func(client *Client) {
+		client.errorCodes = codes
+	}
+}
+
 // ClientOption are functions that are passed into NewClient to
 // modify the behaviour of the Client.
 type ClientOption func(*Client)
@@ -276,6 +388,10 @@ type GraphQLError struct {
 	} `json:"locations"`
 	Path       []interface{}          `json:"path"`
 	Extensions map[string]interface{} `json:"extensions"`
+
+	// codes is the code->sentinel mapping in effect when this error was
+	// produced (the Client's WithErrorCodes, if any), consulted by Is.
+	codes map[string]error
 }
 
 // Error satisfies the Error interface
@@ -301,6 +417,10 @@ type Request struct {
 	// Header represent any request headers that will be set
 	// when the request is made.
 	Header http.Header
+
+	// files attached with WithFiles, sent as a multipart request when
+	// non-empty. Keyed by the dotted path into vars the file belongs at.
+	files map[string]Upload
 }
 
 // NewRequest makes a new Request with the specified string.
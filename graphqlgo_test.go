@@ -54,7 +54,7 @@ func TestRunSimple(t *testing.T) {
 	defer cancel()
 
 	var responseData map[string]string
-	gqerr, err := client.Run(ctx, &Request{q: "query {}"}, &responseData)
+	_, gqerr, err := client.RunRaw(ctx, &Request{q: "query {}"}, &responseData)
 	if err != nil {
 		t.Errorf("clientRun should not return error: %v", err)
 	}
@@ -114,12 +114,12 @@ func TestRunWithOpts(t *testing.T) {
 	defer cancel()
 
 	var responseData map[string]string
-	gqerr, err := client.Run(ctx1, req, &responseData)
+	result, gqerr, err := client.RunRaw(ctx1, req, &responseData)
 	if err != nil {
 		t.Errorf("clientRun should not return error: %v", err)
 	}
 
-	// t.Log(prettyPrint(client.InspectRun))
+	// t.Log(prettyPrint(result.InspectRun))
 	if responseData["something"] != "yes" {
 		t.Errorf("I wanted some response data but got:\n%s\n", prettyPrint(responseData))
 	}
@@ -127,8 +127,8 @@ func TestRunWithOpts(t *testing.T) {
 		t.Errorf("There should be no graphql errors: \n%s\n", prettyPrint(gqerr))
 	}
 
-	// t.Log(prettyPrint(client.InspectRun))
-	reqHeaders, ok := client.InspectRun["ReqHeaders"].(http.Header)
+	// t.Log(prettyPrint(result.InspectRun))
+	reqHeaders, ok := result.InspectRun["ReqHeaders"].(http.Header)
 	if !ok {
 		t.Fatal("Request headers should be able to typecast")
 	}
@@ -159,19 +159,19 @@ func TestRunWithOpts(t *testing.T) {
 	ctx2, cancel2 := context.WithTimeout(ctx, 1*time.Second)
 	defer cancel2()
 
-	_, err = client.Run(ctx2, req2, nil)
+	result, _, err = client.RunRaw(ctx2, req2, nil)
 	if err != nil {
 		t.Errorf("clientRun should not return error: %v", err)
 	}
 
-	reqHeaders, ok = client.InspectRun["ReqHeaders"].(http.Header)
+	reqHeaders, ok = result.InspectRun["ReqHeaders"].(http.Header)
 	if !ok {
 		t.Fatal("Request headers should be able to typecast")
 	}
 
 	_, ok = reqHeaders["X-Some-Fromreq"]
 	if ok {
-		t.Errorf("There should not be 'X-Some-Fromreq' in the query but is. All headers: %s\n", prettyPrint(client.InspectRun["ReqHeaders"]))
+		t.Errorf("There should not be 'X-Some-Fromreq' in the query but is. All headers: %s\n", prettyPrint(result.InspectRun["ReqHeaders"]))
 	}
 }
 
@@ -222,7 +222,7 @@ func TestRunWithGraphQLErrors(t *testing.T) {
 	defer cancel()
 
 	var responseData map[string]string
-	gqerr, err := client.Run(ctx, &Request{q: "query {}"}, &responseData)
+	_, gqerr, err := client.RunRaw(ctx, &Request{q: "query {}"}, &responseData)
 	if err != nil {
 		t.Errorf("clientRun should not return error: %v", err)
 	}
@@ -272,7 +272,7 @@ func TestBadRequest(t *testing.T) {
 	defer cancel()
 
 	var responseData map[string]string
-	gqerr, err := client.Run(ctx, &Request{q: "query {}"}, &responseData)
+	_, gqerr, err := client.RunRaw(ctx, &Request{q: "query {}"}, &responseData)
 	if err == nil {
 		t.Errorf("clientRun should return error")
 	}
@@ -0,0 +1,146 @@
+package graphqlgo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// RoundTrip executes a single GraphQL operation and returns the decoded
+// response alongside the underlying *http.Response (nil on a transport
+// error, e.g. the request never reached the server).
+type RoundTrip func(ctx context.Context, req *Request) (*GraphQLResponse, *http.Response, error)
+
+// Middleware wraps a RoundTrip with additional behaviour (auth, logging,
+// tracing, automatic persisted queries, header injection...). next is the
+// rest of the chain, including the core HTTP transport.
+type Middleware func(next RoundTrip) RoundTrip
+
+// WithMiddleware appends middleware to the chain Client.Run executes
+// through for every attempt. Middleware run in the order given, i.e. the
+// first middleware sees the request first and the response last.
+func WithMiddleware(mws ...Middleware) ClientOption {
+	return func(client *Client) {
+		client.middlewares = append(client.middlewares, mws...)
+	}
+}
+
+// chain builds the RoundTrip that Run invokes for a single attempt: the
+// registered middleware wrapped around the core HTTP transport.
+func (c *Client) chain() RoundTrip {
+	rt := c.coreRoundTrip
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		rt = c.middlewares[i](rt)
+	}
+	return rt
+}
+
+// inspectKey is the context key coreRoundTrip uses to find the InspectData
+// for the current attempt, set by Run.
+type inspectKey struct{}
+
+func withInspect(ctx context.Context, data InspectData) context.Context {
+	return context.WithValue(ctx, inspectKey{}, data)
+}
+
+func inspectFromContext(ctx context.Context) InspectData {
+	data, _ := ctx.Value(inspectKey{}).(InspectData)
+	return data
+}
+
+// coreRoundTrip is the innermost RoundTrip: it encodes req, performs the
+// HTTP POST and decodes the GraphQL envelope. It records diagnostics on
+// the InspectData stashed in ctx by Run, if any.
+func (c *Client) coreRoundTrip(ctx context.Context, req *Request) (*GraphQLResponse, *http.Response, error) {
+	inspect := inspectFromContext(ctx)
+
+	if len(req.files) > 0 {
+		return c.multipartRoundTrip(ctx, req, inspect)
+	}
+
+	requestBodyObj := RequestBody{
+		Query:         req.q,
+		Variables:     req.vars,
+		OperationName: req.opName,
+	}
+	if inspect != nil {
+		inspect["ReqBody"] = &requestBodyObj
+	}
+
+	bodyBytes, err := json.Marshal(requestBodyObj)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "encode body")
+	}
+
+	r, err := http.NewRequest(http.MethodPost, c.Endpoint, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, nil, err
+	}
+	r.Close = c.closeReq
+
+	// Adds headers defined on the client.
+	for key, values := range c.Header {
+		for _, value := range values {
+			r.Header.Add(key, value)
+		}
+	}
+
+	// Adds headers defined on the current request.
+	for key, values := range req.Header {
+		for _, value := range values {
+			r.Header.Add(key, value)
+		}
+	}
+	if inspect != nil {
+		inspect["ReqHeaders"] = r.Header
+	}
+
+	r = r.WithContext(ctx)
+	res, err := c.httpClient.Do(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+
+	if inspect != nil {
+		inspect["ResStatusCode"] = res.StatusCode
+		inspect["ResHeaders"] = res.Header
+		inspect["ResCookies"] = res.Cookies() // should return func to be consistent with http?
+		inspect["ResContentLength"] = res.ContentLength
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, res, fmt.Errorf("HTTP Error %v: graphql server returned a non-200 status code", res.StatusCode)
+	}
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, res.Body); err != nil {
+		return nil, res, errors.Wrap(err, "reading body")
+	}
+	if inspect != nil {
+		inspect["ResBody"] = buf.String()
+	}
+
+	var gr GraphQLResponse
+	if err := json.NewDecoder(&buf).Decode(&gr); err != nil {
+		return nil, res, errors.Wrap(err, "decoding response")
+	}
+
+	return &gr, res, nil
+}
+
+// decodeInto re-marshals data (as decoded generically from the "data"
+// field by coreRoundTrip) into resp. RoundTrip has no knowledge of the
+// caller's response type, so Run does this once the chain returns.
+func decodeInto(data interface{}, resp interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, resp)
+}
@@ -0,0 +1,103 @@
+package graphqlgo
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMiddlewareOrderAndHeaderInjection(t *testing.T) {
+	var gotHeader string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get("X-Injected")
+		io.WriteString(w, `{"data": {"something": "yes"}}`)
+	}))
+	defer srv.Close()
+
+	var order []string
+	injectHeader := func(next RoundTrip) RoundTrip {
+		return func(ctx context.Context, req *Request) (*GraphQLResponse, *http.Response, error) {
+			order = append(order, "inject-before")
+			req.Header.Set("X-Injected", "mw-value")
+			gr, res, err := next(ctx, req)
+			order = append(order, "inject-after")
+			return gr, res, err
+		}
+	}
+	logging := func(next RoundTrip) RoundTrip {
+		return func(ctx context.Context, req *Request) (*GraphQLResponse, *http.Response, error) {
+			order = append(order, "log-before")
+			gr, res, err := next(ctx, req)
+			order = append(order, "log-after")
+			return gr, res, err
+		}
+	}
+
+	client := NewClient(srv.URL, WithMiddleware(injectHeader, logging))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	req := NewRequest("query {}")
+	var responseData map[string]string
+	_, gqerr, err := client.RunRaw(ctx, req, &responseData)
+	if err != nil {
+		t.Fatalf("clientRun should not return error: %v", err)
+	}
+	if gqerr != nil {
+		t.Errorf("there should be no graphql errors: %v", gqerr)
+	}
+	if responseData["something"] != "yes" {
+		t.Errorf("unexpected response data: %#v", responseData)
+	}
+
+	if gotHeader != "mw-value" {
+		t.Errorf("expected injected header to reach the server, got %q", gotHeader)
+	}
+
+	wantOrder := []string{"inject-before", "log-before", "log-after", "inject-after"}
+	if len(order) != len(wantOrder) {
+		t.Fatalf("unexpected call order: %v", order)
+	}
+	for i, step := range wantOrder {
+		if order[i] != step {
+			t.Errorf("call order[%d] = %q, want %q (full: %v)", i, order[i], step, order)
+		}
+	}
+}
+
+func TestMiddlewareSeesNon200Errors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	var sawErr bool
+	observe := func(next RoundTrip) RoundTrip {
+		return func(ctx context.Context, req *Request) (*GraphQLResponse, *http.Response, error) {
+			_, _, err := next(ctx, req)
+			if err != nil {
+				sawErr = true
+			}
+			return nil, nil, err
+		}
+	}
+
+	client := NewClient(srv.URL, WithMiddleware(observe))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	_, _, err := client.RunRaw(ctx, NewRequest("query {}"), nil)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !sawErr {
+		t.Error("expected middleware to observe the transport error")
+	}
+}
@@ -0,0 +1,149 @@
+package graphqlgo
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures the automatic retries performed by Client.Run.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times Run will try the request,
+	// including the first attempt. Values <= 1 disable retrying.
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry; subsequent retries
+	// back off exponentially from this value.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the computed backoff delay.
+	MaxDelay time.Duration
+
+	// Jitter, when true, randomizes the computed delay between 0 and the
+	// backoff value to spread out retries.
+	Jitter bool
+
+	// RetriableStatusCodes are HTTP status codes that should be retried.
+	// DefaultRetryPolicy sets 429, 502, 503 and 504; a nil map retries no
+	// status code.
+	RetriableStatusCodes map[int]bool
+
+	// RetriableCodes are GraphQL errors.extensions.code values that should
+	// be retried. DefaultRetryPolicy sets {"RATE_LIMITED": true}; a nil map
+	// retries no GraphQL error code.
+	RetriableCodes map[string]bool
+
+	// IsRetriable, when set, overrides the default retriability check
+	// above. It receives the HTTP response (nil on a transport error), the
+	// GraphQL errors decoded from the response (nil if none) and the error
+	// for the attempt, and reports whether Run should retry.
+	IsRetriable func(res *http.Response, gqlErrs []GraphQLError, err error) bool
+}
+
+// DefaultRetryPolicy returns a RetryPolicy with sensible defaults: 3
+// attempts, exponential backoff from 200ms up to 5s with jitter, retrying
+// transport errors, 429/502/503/504 responses and RATE_LIMITED GraphQL
+// errors.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   200 * time.Millisecond,
+		MaxDelay:    5 * time.Second,
+		Jitter:      true,
+		RetriableStatusCodes: map[int]bool{
+			http.StatusTooManyRequests:    true,
+			http.StatusBadGateway:         true,
+			http.StatusServiceUnavailable: true,
+			http.StatusGatewayTimeout:     true,
+		},
+		RetriableCodes: map[string]bool{
+			"RATE_LIMITED": true,
+		},
+	}
+}
+
+// WithRetry enables automatic retries on Client.Run according to policy.
+func WithRetry(policy RetryPolicy) ClientOption {
+	return func(client *Client) {
+		client.retryPolicy = &policy
+	}
+}
+
+// retriable reports whether an attempt that produced res (nil on a
+// transport error), gqlErrs and err should be retried.
+func (p RetryPolicy) retriable(res *http.Response, gqlErrs []GraphQLError, err error) bool {
+	if p.IsRetriable != nil {
+		return p.IsRetriable(res, gqlErrs, err)
+	}
+
+	if err != nil && res == nil {
+		// transport-level error: dial failure, timeout, connection reset...
+		return true
+	}
+
+	if res != nil && p.RetriableStatusCodes[res.StatusCode] {
+		return true
+	}
+
+	for _, gqlErr := range gqlErrs {
+		if code, ok := gqlErr.Extensions["code"].(string); ok && p.RetriableCodes[code] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// backoff computes the delay before the given attempt (1 for the delay
+// before the first retry). retryAfter, when non-zero, takes precedence
+// over the computed exponential delay.
+func (p RetryPolicy) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+
+	delay := time.Duration(float64(p.BaseDelay) * math.Pow(2, float64(attempt-1)))
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if p.Jitter && delay > 0 {
+		delay = time.Duration(rand.Int63n(int64(delay) + 1))
+	}
+	return delay
+}
+
+// parseRetryAfter parses a Retry-After header in either the delay-seconds
+// or HTTP-date form, returning 0 if it is absent or unparseable.
+func parseRetryAfter(header http.Header) time.Duration {
+	v := header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if when, err := http.ParseTime(v); err == nil {
+		if d := time.Until(when); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// sleep waits for d or until ctx is cancelled, whichever comes first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,143 @@
+package graphqlgo
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunRetriesOn429(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal("ReadAll should not return error:", err)
+		}
+		wantQuery := `{"query":"query {}","variables":null,"operationName":null}`
+		if string(body) != wantQuery {
+			t.Errorf("attempt %d: wanted %q, got %q", calls, wantQuery, string(body))
+		}
+
+		if calls < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		io.WriteString(w, `{"data": {"something": "yes"}}`)
+	}))
+	defer srv.Close()
+
+	policy := DefaultRetryPolicy()
+	policy.MaxAttempts = 3
+	policy.BaseDelay = time.Millisecond
+	policy.MaxDelay = 10 * time.Millisecond
+
+	client := NewClient(srv.URL, WithRetry(policy))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var responseData map[string]string
+	result, gqerr, err := client.RunRaw(ctx, &Request{q: "query {}"}, &responseData)
+	if err != nil {
+		t.Fatalf("clientRun should not return error: %v", err)
+	}
+	if gqerr != nil {
+		t.Errorf("there should be no graphql errors: %v", gqerr)
+	}
+	if responseData["something"] != "yes" {
+		t.Errorf("unexpected response data: %#v", responseData)
+	}
+
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+
+	if len(result.InspectRuns) != 3 {
+		t.Errorf("expected 3 InspectRuns entries, got %d", len(result.InspectRuns))
+	}
+}
+
+func TestRunWithFilesNotRetried(t *testing.T) {
+	var calls int
+	var uploadedBodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatal("ParseMultipartForm should not return error:", err)
+		}
+		file, _, err := r.FormFile("0")
+		if err != nil {
+			t.Fatal("FormFile should not return error:", err)
+		}
+		defer file.Close()
+		content, err := ioutil.ReadAll(file)
+		if err != nil {
+			t.Fatal("ReadAll should not return error:", err)
+		}
+		uploadedBodies = append(uploadedBodies, string(content))
+
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	policy := DefaultRetryPolicy()
+	policy.MaxAttempts = 3
+	policy.BaseDelay = time.Millisecond
+	policy.MaxDelay = 10 * time.Millisecond
+
+	client := NewClient(srv.URL, WithRetry(policy))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	req := NewRequest("mutation { upload(file: $file) }", WithFiles(map[string]Upload{
+		"file": {Reader: strings.NewReader("file contents")},
+	}))
+
+	result, _, err := client.RunRaw(ctx, req, nil)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if calls != 1 {
+		t.Errorf("expected exactly 1 call for a request with files, got %d", calls)
+	}
+	if len(result.InspectRuns) != 1 {
+		t.Errorf("expected 1 InspectRuns entry, got %d", len(result.InspectRuns))
+	}
+	if len(uploadedBodies) != 1 || uploadedBodies[0] != "file contents" {
+		t.Errorf("expected the single attempt to upload the full file, got %#v", uploadedBodies)
+	}
+}
+
+func TestRunNoRetryByDefault(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	result, _, err := client.RunRaw(ctx, &Request{q: "query {}"}, nil)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 call without a RetryPolicy, got %d", calls)
+	}
+	if len(result.InspectRuns) != 1 {
+		t.Errorf("expected 1 InspectRuns entry, got %d", len(result.InspectRuns))
+	}
+}
@@ -0,0 +1,304 @@
+package graphqlgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+)
+
+// Subprotocol identifies which websocket subprotocol Subscribe speaks with
+// the server.
+type Subprotocol string
+
+const (
+	// SubprotocolGraphQLWS is the original "graphql-ws" subprotocol
+	// implemented by subscriptions-transport-ws and gqlgen:
+	// connection_init/connection_ack, start/data/error/complete, stop.
+	SubprotocolGraphQLWS Subprotocol = "graphql-ws"
+
+	// SubprotocolGraphQLTransportWS is the newer "graphql-transport-ws"
+	// subprotocol: connection_init/connection_ack, subscribe/next/error/
+	// complete, ping/pong.
+	SubprotocolGraphQLTransportWS Subprotocol = "graphql-transport-ws"
+)
+
+// InitPayload is sent as the payload of the connection_init message.
+type InitPayload map[string]interface{}
+
+// SubscriptionMessage is delivered on the channel returned by Subscribe for
+// every data frame the server sends for the subscription.
+type SubscriptionMessage struct {
+	Data   interface{}
+	Errors []GraphQLError
+}
+
+// wsMessage is the envelope shared by both graphql-ws and
+// graphql-transport-ws frames.
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// WSFrameLog is InspectData["WSFrames"] for a subscription opened with
+// Subscribe: the ordered sent/received frame history, safe to read with
+// Frames while the subscription is still running. Subscribe's background
+// goroutines keep appending to it for the life of the subscription, so a
+// snapshot taken before the channel closes may be incomplete.
+type WSFrameLog struct {
+	mu     sync.Mutex
+	frames []InspectData
+}
+
+// Frames returns a snapshot of the frames recorded so far.
+func (l *WSFrameLog) Frames() []InspectData {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]InspectData, len(l.frames))
+	copy(out, l.frames)
+	return out
+}
+
+func (l *WSFrameLog) record(direction string, msg wsMessage) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.frames = append(l.frames, InspectData{"direction": direction, "message": msg})
+}
+
+// Subscribe opens a websocket connection to c.Endpoint and streams results
+// for a subscription operation on the returned channel. newData must return
+// a pointer to decode a single message's "data" field into; it is called
+// once per incoming message so each SubscriptionMessage.Data is independent.
+//
+// The subprotocol is selected with WithSubprotocol (default
+// SubprotocolGraphQLWS) and the connection_init payload with
+// WithInitPayload.
+//
+// The returned InspectData carries the connection's frame history under
+// "WSFrames" as a *WSFrameLog rather than on the Client, consistent with
+// Run returning its diagnostics in RunResult instead of storing them. The
+// log keeps growing for the life of the subscription and is safe to read
+// concurrently with Subscribe's background goroutines; call its Frames
+// method rather than assuming the history is complete until the channel
+// closes.
+//
+// The channel is closed when the server sends "complete", the connection is
+// closed, or ctx is cancelled. Cancelling ctx sends a "stop"/"complete"
+// message and closes the socket.
+func (c *Client) Subscribe(ctx context.Context, req *Request, newData func() interface{}) (<-chan SubscriptionMessage, InspectData, error) {
+	select {
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	default:
+	}
+
+	protocol := c.subprotocol
+	if protocol == "" {
+		protocol = SubprotocolGraphQLWS
+	}
+
+	dialer := c.wsDialer
+	if dialer == nil {
+		dialer = websocket.DefaultDialer
+	}
+	dialerCopy := *dialer
+	dialerCopy.Subprotocols = []string{string(protocol)}
+	dialer = &dialerCopy
+
+	header := http.Header{}
+	for key, values := range c.Header {
+		for _, value := range values {
+			header.Add(key, value)
+		}
+	}
+	for key, values := range req.Header {
+		for _, value := range values {
+			header.Add(key, value)
+		}
+	}
+
+	url := toWSURL(c.Endpoint)
+
+	frameLog := &WSFrameLog{}
+	inspect := InspectData{"WSURL": url, "WSSubprotocol": string(protocol), "WSFrames": frameLog}
+
+	conn, _, err := dialer.Dial(url, header)
+	if err != nil {
+		return nil, inspect, errors.Wrap(err, "dial websocket")
+	}
+
+	// writeMu serializes conn writes: the reader goroutine (replying to
+	// "ping" with "pong") and the ctx-cancellation goroutine (sending
+	// "stop"/"complete") can both write below, and gorilla/websocket
+	// forbids concurrent writers.
+	var writeMu sync.Mutex
+	sendFrame := func(msg wsMessage) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		frameLog.record("sent", msg)
+		return conn.WriteJSON(msg)
+	}
+
+	initMsg := wsMessage{Type: "connection_init"}
+	if c.initPayload != nil {
+		payload, err := json.Marshal(c.initPayload)
+		if err != nil {
+			conn.Close()
+			return nil, inspect, errors.Wrap(err, "encode connection_init payload")
+		}
+		initMsg.Payload = payload
+	}
+	if err := sendFrame(initMsg); err != nil {
+		conn.Close()
+		return nil, inspect, errors.Wrap(err, "send connection_init")
+	}
+
+	ack, err := readWSMessage(conn)
+	if err != nil {
+		conn.Close()
+		return nil, inspect, errors.Wrap(err, "waiting for connection_ack")
+	}
+	frameLog.record("received", ack)
+	if ack.Type != "connection_ack" {
+		conn.Close()
+		return nil, inspect, fmt.Errorf("expected connection_ack, got %q", ack.Type)
+	}
+
+	id := strconv.FormatUint(atomic.AddUint64(&subscriptionIDs, 1), 10)
+	startType, payload := subscribeFrame(protocol, req)
+	startMsg := wsMessage{ID: id, Type: startType, Payload: payload}
+	if err := sendFrame(startMsg); err != nil {
+		conn.Close()
+		return nil, inspect, errors.Wrap(err, "send subscribe")
+	}
+
+	out := make(chan SubscriptionMessage)
+
+	go func() {
+		defer close(out)
+		defer conn.Close()
+
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				stopType := "stop"
+				if protocol == SubprotocolGraphQLTransportWS {
+					stopType = "complete"
+				}
+				sendFrame(wsMessage{ID: id, Type: stopType})
+				conn.Close()
+			case <-done:
+			}
+		}()
+		defer close(done)
+
+		for {
+			msg, err := readWSMessage(conn)
+			if err != nil {
+				return
+			}
+			frameLog.record("received", msg)
+
+			switch msg.Type {
+			case "data", "next":
+				data := newData()
+				var gr GraphQLResponse
+				gr.Data = data
+				if len(msg.Payload) > 0 {
+					if err := json.Unmarshal(msg.Payload, &gr); err != nil {
+						out <- SubscriptionMessage{Errors: []GraphQLError{{Message: err.Error()}}}
+						continue
+					}
+				}
+				out <- SubscriptionMessage{Data: data, Errors: gr.Errors}
+			case "error":
+				var gqlErr GraphQLError
+				if err := json.Unmarshal(msg.Payload, &gqlErr); err != nil {
+					gqlErr.Message = string(msg.Payload)
+				}
+				out <- SubscriptionMessage{Errors: []GraphQLError{gqlErr}}
+			case "complete":
+				return
+			case "ka", "pong":
+				// keep-alive / pong, nothing to deliver
+			case "ping":
+				sendFrame(wsMessage{Type: "pong"})
+			}
+		}
+	}()
+
+	return out, inspect, nil
+}
+
+// subscriptionIDs generates unique per-process subscription IDs.
+var subscriptionIDs uint64
+
+// subscribeFrame builds the message type and payload used to start a
+// subscription for the given subprotocol.
+func subscribeFrame(protocol Subprotocol, req *Request) (string, json.RawMessage) {
+	body := RequestBody{
+		Query:         req.q,
+		Variables:     req.vars,
+		OperationName: req.opName,
+	}
+	payload, _ := json.Marshal(body)
+
+	if protocol == SubprotocolGraphQLTransportWS {
+		return "subscribe", payload
+	}
+	return "start", payload
+}
+
+// readWSMessage reads and decodes the next frame from conn.
+func readWSMessage(conn *websocket.Conn) (wsMessage, error) {
+	var msg wsMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		return wsMessage{}, err
+	}
+	return msg, nil
+}
+
+// toWSURL rewrites an http(s):// endpoint to its ws(s):// equivalent.
+// Endpoints already using ws/wss are left untouched.
+func toWSURL(endpoint string) string {
+	switch {
+	case strings.HasPrefix(endpoint, "https://"):
+		return "wss://" + strings.TrimPrefix(endpoint, "https://")
+	case strings.HasPrefix(endpoint, "http://"):
+		return "ws://" + strings.TrimPrefix(endpoint, "http://")
+	default:
+		return endpoint
+	}
+}
+
+// WithInitPayload specifies the payload sent with the connection_init
+// message when a subscription is opened with Subscribe.
+func WithInitPayload(payload InitPayload) ClientOption {
+	return func(client *Client) {
+		client.initPayload = payload
+	}
+}
+
+// WithSubprotocol selects the websocket subprotocol Subscribe speaks.
+// Defaults to SubprotocolGraphQLWS.
+func WithSubprotocol(protocol Subprotocol) ClientOption {
+	return func(client *Client) {
+		client.subprotocol = protocol
+	}
+}
+
+// WithWSDialer specifies the websocket.Dialer used by Subscribe.
+func WithWSDialer(dialer *websocket.Dialer) ClientOption {
+	return func(client *Client) {
+		client.wsDialer = dialer
+	}
+}
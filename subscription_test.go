@@ -0,0 +1,215 @@
+package graphqlgo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var upgrader = websocket.Upgrader{}
+
+// graphqlWSTestServer speaks the legacy graphql-ws subprotocol and sends a
+// single "hi" data message before completing.
+func graphqlWSTestServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Fatalf("upgrade: %v", err)
+		}
+		defer conn.Close()
+
+		var init wsMessage
+		if err := conn.ReadJSON(&init); err != nil {
+			t.Fatalf("read connection_init: %v", err)
+		}
+		if init.Type != "connection_init" {
+			t.Fatalf("expected connection_init, got %q", init.Type)
+		}
+		if err := conn.WriteJSON(wsMessage{Type: "connection_ack"}); err != nil {
+			t.Fatalf("write connection_ack: %v", err)
+		}
+
+		var start wsMessage
+		if err := conn.ReadJSON(&start); err != nil {
+			t.Fatalf("read start: %v", err)
+		}
+		if start.Type != "start" {
+			t.Fatalf("expected start, got %q", start.Type)
+		}
+
+		conn.WriteJSON(wsMessage{
+			ID:      start.ID,
+			Type:    "data",
+			Payload: json.RawMessage(`{"data":{"greeting":"hi"}}`),
+		})
+		conn.WriteJSON(wsMessage{ID: start.ID, Type: "complete"})
+	}))
+}
+
+func TestSubscribeGraphQLWS(t *testing.T) {
+	srv := graphqlWSTestServer(t)
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	req := NewRequest("subscription { greeting }")
+
+	type result struct {
+		Greeting string `json:"greeting"`
+	}
+
+	msgs, inspect, err := client.Subscribe(ctx, req, func() interface{} { return &result{} })
+	if err != nil {
+		t.Fatalf("Subscribe returned error: %v", err)
+	}
+	if inspect["WSSubprotocol"] != string(SubprotocolGraphQLWS) {
+		t.Errorf("expected WSSubprotocol %q, got %v", SubprotocolGraphQLWS, inspect["WSSubprotocol"])
+	}
+
+	select {
+	case msg, ok := <-msgs:
+		if !ok {
+			t.Fatal("channel closed before delivering a message")
+		}
+		if len(msg.Errors) != 0 {
+			t.Fatalf("unexpected errors: %v", msg.Errors)
+		}
+		data, ok := msg.Data.(*result)
+		if !ok {
+			t.Fatalf("unexpected data type: %#v", msg.Data)
+		}
+		if data.Greeting != "hi" {
+			t.Errorf("expected greeting %q, got %q", "hi", data.Greeting)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for subscription message")
+	}
+
+	select {
+	case _, ok := <-msgs:
+		if ok {
+			t.Error("expected channel to close after complete")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for channel close")
+	}
+}
+
+// graphqlTransportWSTestServer speaks the newer graphql-transport-ws
+// subprotocol and, unlike upgrader, offers it via Sec-WebSocket-Protocol;
+// the handler fails the test if the connection did not negotiate it.
+func graphqlTransportWSTestServer(t *testing.T) *httptest.Server {
+	strictUpgrader := websocket.Upgrader{
+		Subprotocols: []string{string(SubprotocolGraphQLTransportWS)},
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := strictUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Fatalf("upgrade: %v", err)
+		}
+		defer conn.Close()
+
+		if conn.Subprotocol() != string(SubprotocolGraphQLTransportWS) {
+			t.Fatalf("expected negotiated subprotocol %q, got %q", SubprotocolGraphQLTransportWS, conn.Subprotocol())
+		}
+
+		var init wsMessage
+		if err := conn.ReadJSON(&init); err != nil {
+			t.Fatalf("read connection_init: %v", err)
+		}
+		if init.Type != "connection_init" {
+			t.Fatalf("expected connection_init, got %q", init.Type)
+		}
+		if err := conn.WriteJSON(wsMessage{Type: "connection_ack"}); err != nil {
+			t.Fatalf("write connection_ack: %v", err)
+		}
+
+		var sub wsMessage
+		if err := conn.ReadJSON(&sub); err != nil {
+			t.Fatalf("read subscribe: %v", err)
+		}
+		if sub.Type != "subscribe" {
+			t.Fatalf("expected subscribe, got %q", sub.Type)
+		}
+
+		conn.WriteJSON(wsMessage{
+			ID:      sub.ID,
+			Type:    "next",
+			Payload: json.RawMessage(`{"data":{"greeting":"hi"}}`),
+		})
+		conn.WriteJSON(wsMessage{ID: sub.ID, Type: "complete"})
+	}))
+}
+
+func TestSubscribeGraphQLTransportWS(t *testing.T) {
+	srv := graphqlTransportWSTestServer(t)
+	defer srv.Close()
+
+	client := NewClient(srv.URL, WithSubprotocol(SubprotocolGraphQLTransportWS))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	req := NewRequest("subscription { greeting }")
+
+	type result struct {
+		Greeting string `json:"greeting"`
+	}
+
+	msgs, inspect, err := client.Subscribe(ctx, req, func() interface{} { return &result{} })
+	if err != nil {
+		t.Fatalf("Subscribe returned error: %v", err)
+	}
+	if inspect["WSSubprotocol"] != string(SubprotocolGraphQLTransportWS) {
+		t.Errorf("expected WSSubprotocol %q, got %v", SubprotocolGraphQLTransportWS, inspect["WSSubprotocol"])
+	}
+
+	select {
+	case msg, ok := <-msgs:
+		if !ok {
+			t.Fatal("channel closed before delivering a message")
+		}
+		if len(msg.Errors) != 0 {
+			t.Fatalf("unexpected errors: %v", msg.Errors)
+		}
+		data, ok := msg.Data.(*result)
+		if !ok {
+			t.Fatalf("unexpected data type: %#v", msg.Data)
+		}
+		if data.Greeting != "hi" {
+			t.Errorf("expected greeting %q, got %q", "hi", data.Greeting)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for subscription message")
+	}
+
+	select {
+	case _, ok := <-msgs:
+		if ok {
+			t.Error("expected channel to close after complete")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for channel close")
+	}
+}
+
+func TestToWSURL(t *testing.T) {
+	cases := map[string]string{
+		"https://example.com/graphql": "wss://example.com/graphql",
+		"http://example.com/graphql":  "ws://example.com/graphql",
+		"ws://example.com/graphql":    "ws://example.com/graphql",
+	}
+	for in, want := range cases {
+		if got := toWSURL(in); got != want {
+			t.Errorf("toWSURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
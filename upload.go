@@ -0,0 +1,324 @@
+package graphqlgo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Upload is a file to send as part of a GraphQL multipart request,
+// implementing the jaydenseric GraphQL multipart request spec
+// (https://github.com/jaydenseric/graphql-multipart-request-spec). Attach
+// it to a Request with WithFiles.
+type Upload struct {
+	io.Reader
+	Filename    string
+	ContentType string
+}
+
+// WithFiles attaches files to the request for upload per the GraphQL
+// multipart request spec. files is keyed by the dotted path into the
+// request's variables where the Upload scalar belongs, e.g. "file" for
+// variables.file, or "files.0" for the first entry of a variables.files
+// list -- see MultiUpload to build that for a slice of files. When files
+// is non-empty, Run sends this request as multipart/form-data instead of
+// application/json, and the request is never retried regardless of any
+// RetryPolicy set with WithRetry -- see RunRaw.
+func WithFiles(files map[string]Upload) RequestOption {
+	return func(r *Request) {
+		r.files = files
+	}
+}
+
+// MultiUpload builds the map WithFiles expects for a list of files living
+// at a single variables path, producing keys "path.0", "path.1" and so on
+// for variables.<path> being a list.
+func MultiUpload(path string, uploads []Upload) map[string]Upload {
+	files := make(map[string]Upload, len(uploads))
+	for i, u := range uploads {
+		files[fmt.Sprintf("%s.%d", path, i)] = u
+	}
+	return files
+}
+
+// multipartRoundTrip encodes req per the GraphQL multipart request spec:
+// field "operations" holds the usual {query,variables,operationName} body
+// with each file's variables slot nulled out, field "map" links each file
+// part back to its variables path, followed by one part per file.
+func (c *Client) multipartRoundTrip(ctx context.Context, req *Request, inspect InspectData) (*GraphQLResponse, *http.Response, error) {
+	vars := cloneVars(req.vars)
+
+	paths := make([]string, 0, len(req.files))
+	for path := range req.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	fileMap := make(map[string][]string, len(paths))
+	for i, path := range paths {
+		if err := cloneVarPath(vars, path); err != nil {
+			return nil, nil, errors.Wrapf(err, "copying variables.%s for upload", path)
+		}
+		if err := setVarPath(vars, path, nil); err != nil {
+			return nil, nil, errors.Wrapf(err, "setting nil for upload at variables.%s", path)
+		}
+		fileMap[strconv.Itoa(i)] = []string{"variables." + path}
+	}
+
+	operations := RequestBody{
+		Query:         req.q,
+		Variables:     vars,
+		OperationName: req.opName,
+	}
+	if inspect != nil {
+		inspect["ReqBody"] = &operations
+		inspect["ReqMultipartMap"] = fileMap
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	opField, err := mw.CreateFormField("operations")
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "create operations field")
+	}
+	if err := json.NewEncoder(opField).Encode(operations); err != nil {
+		return nil, nil, errors.Wrap(err, "encode operations")
+	}
+
+	mapField, err := mw.CreateFormField("map")
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "create map field")
+	}
+	if err := json.NewEncoder(mapField).Encode(fileMap); err != nil {
+		return nil, nil, errors.Wrap(err, "encode map")
+	}
+
+	for i, path := range paths {
+		key := strconv.Itoa(i)
+		upload := req.files[path]
+		part, err := mw.CreatePart(uploadPartHeader(key, upload))
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "create part for variables.%s", path)
+		}
+		if _, err := io.Copy(part, upload); err != nil {
+			return nil, nil, errors.Wrapf(err, "write part for variables.%s", path)
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		return nil, nil, errors.Wrap(err, "close multipart writer")
+	}
+
+	r, err := http.NewRequest(http.MethodPost, c.Endpoint, &body)
+	if err != nil {
+		return nil, nil, err
+	}
+	r.Close = c.closeReq
+
+	// Adds headers defined on the client, except Content-Type which the
+	// multipart writer owns (it carries the boundary).
+	for key, values := range c.Header {
+		if key == "Content-Type" {
+			continue
+		}
+		for _, value := range values {
+			r.Header.Add(key, value)
+		}
+	}
+
+	// Adds headers defined on the current request.
+	for key, values := range req.Header {
+		for _, value := range values {
+			r.Header.Add(key, value)
+		}
+	}
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	if inspect != nil {
+		inspect["ReqHeaders"] = r.Header
+	}
+
+	r = r.WithContext(ctx)
+	res, err := c.httpClient.Do(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+
+	if inspect != nil {
+		inspect["ResStatusCode"] = res.StatusCode
+		inspect["ResHeaders"] = res.Header
+		inspect["ResCookies"] = res.Cookies()
+		inspect["ResContentLength"] = res.ContentLength
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, res, fmt.Errorf("HTTP Error %v: graphql server returned a non-200 status code", res.StatusCode)
+	}
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, res.Body); err != nil {
+		return nil, res, errors.Wrap(err, "reading body")
+	}
+	if inspect != nil {
+		inspect["ResBody"] = buf.String()
+	}
+
+	var gr GraphQLResponse
+	if err := json.NewDecoder(&buf).Decode(&gr); err != nil {
+		return nil, res, errors.Wrap(err, "decoding response")
+	}
+
+	return &gr, res, nil
+}
+
+// uploadPartHeader builds the Content-Disposition/Content-Type headers for
+// a file part, keyed the same way as its entry in the "map" field.
+func uploadPartHeader(key string, u Upload) textproto.MIMEHeader {
+	filename := u.Filename
+	if filename == "" {
+		filename = key
+	}
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name=%q; filename=%q`, key, filename))
+	if u.ContentType != "" {
+		h.Set("Content-Type", u.ContentType)
+	}
+	return h
+}
+
+// cloneVars makes a shallow copy of the top-level vars map, or a fresh
+// empty map if the caller didn't set any variables at all -- a request
+// with files attached always has somewhere to null out the upload slots
+// per the GraphQL multipart request spec, even when those slots are the
+// only variables. On its own this isn't enough to protect nested paths
+// ("files.0", "input.file") from the nulling out setVarPath does for
+// uploads -- those still share the caller's backing slices/maps.
+// cloneVarPath clones the containers along such a path before it's
+// nulled.
+func cloneVars(vars map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(vars))
+	for k, v := range vars {
+		out[k] = v
+	}
+	return out
+}
+
+// cloneVarPath walks path within vars, replacing each map/slice
+// container it passes through with a shallow copy of itself so a
+// subsequent setVarPath(vars, path, nil) only nulls out the clone's slot
+// and leaves any container the caller's original variables share at
+// that path untouched. A map segment missing along the way is created
+// rather than treated as an error -- the caller needn't have
+// pre-populated the variable an upload belongs at. A missing or
+// out-of-range list segment is still an error: unlike a map key, there's
+// no sensible value to invent for an uncreated list element.
+func cloneVarPath(vars map[string]interface{}, path string) error {
+	return cloneVarSegment(vars, strings.Split(path, "."))
+}
+
+func cloneVarSegment(container interface{}, segments []string) error {
+	seg := segments[0]
+
+	switch c := container.(type) {
+	case map[string]interface{}:
+		if len(segments) == 1 {
+			return nil
+		}
+		child, ok := c[seg]
+		if !ok {
+			child = map[string]interface{}{}
+		} else {
+			cloned, err := cloneVarContainer(child)
+			if err != nil {
+				return err
+			}
+			child = cloned
+		}
+		c[seg] = child
+		return cloneVarSegment(child, segments[1:])
+	case []interface{}:
+		idx, err := strconv.Atoi(seg)
+		if err != nil || idx < 0 || idx >= len(c) {
+			return fmt.Errorf("index %q out of range in variables", seg)
+		}
+		if len(segments) == 1 {
+			return nil
+		}
+		cloned, err := cloneVarContainer(c[idx])
+		if err != nil {
+			return err
+		}
+		c[idx] = cloned
+		return cloneVarSegment(cloned, segments[1:])
+	default:
+		return fmt.Errorf("cannot descend into variables at %q", seg)
+	}
+}
+
+// cloneVarContainer makes a shallow copy of a map or slice value found
+// while walking a variables path.
+func cloneVarContainer(v interface{}) (interface{}, error) {
+	switch c := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(c))
+		for k, vv := range c {
+			out[k] = vv
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(c))
+		copy(out, c)
+		return out, nil
+	default:
+		return nil, fmt.Errorf("cannot descend into variables: %T is not a map or list", v)
+	}
+}
+
+// setVarPath sets the value at a dot-separated path (e.g. "file" or
+// "files.0") within vars. Each segment is read against the container it
+// meets: an index into a []interface{}, or a key of a
+// map[string]interface{}. Every segment must already exist, except a
+// final map key, which is created if missing.
+func setVarPath(vars map[string]interface{}, path string, v interface{}) error {
+	return setVarSegment(vars, strings.Split(path, "."), v)
+}
+
+func setVarSegment(container interface{}, segments []string, v interface{}) error {
+	seg := segments[0]
+
+	switch c := container.(type) {
+	case map[string]interface{}:
+		if len(segments) == 1 {
+			c[seg] = v
+			return nil
+		}
+		child, ok := c[seg]
+		if !ok {
+			return fmt.Errorf("path %q not found in variables", seg)
+		}
+		return setVarSegment(child, segments[1:], v)
+	case []interface{}:
+		idx, err := strconv.Atoi(seg)
+		if err != nil || idx < 0 || idx >= len(c) {
+			return fmt.Errorf("index %q out of range in variables", seg)
+		}
+		if len(segments) == 1 {
+			c[idx] = v
+			return nil
+		}
+		return setVarSegment(c[idx], segments[1:], v)
+	default:
+		return fmt.Errorf("cannot descend into variables at %q", seg)
+	}
+}
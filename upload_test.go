@@ -0,0 +1,155 @@
+package graphqlgo
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunWithFileUpload(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(10 << 20); err != nil {
+			t.Fatalf("ParseMultipartForm: %v", err)
+		}
+
+		operations := r.FormValue("operations")
+		wantOps := `{"query":"mutation upload($file: Upload!) { upload(file: $file) }","variables":{"file":null},"operationName":null}`
+		if strings.TrimSpace(operations) != wantOps {
+			t.Errorf("operations = %q, want %q", operations, wantOps)
+		}
+
+		gotMap := strings.TrimSpace(r.FormValue("map"))
+		wantMap := `{"0":["variables.file"]}`
+		if gotMap != wantMap {
+			t.Errorf("map = %q, want %q", gotMap, wantMap)
+		}
+
+		f, header, err := r.FormFile("0")
+		if err != nil {
+			t.Fatalf("FormFile: %v", err)
+		}
+		defer f.Close()
+		if header.Filename != "hello.txt" {
+			t.Errorf("filename = %q, want %q", header.Filename, "hello.txt")
+		}
+		content, err := ioutil.ReadAll(f)
+		if err != nil {
+			t.Fatalf("ReadAll: %v", err)
+		}
+		if string(content) != "hello upload" {
+			t.Errorf("content = %q, want %q", string(content), "hello upload")
+		}
+
+		io.WriteString(w, `{"data": {"upload": true}}`)
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+
+	req := NewRequest(
+		"mutation upload($file: Upload!) { upload(file: $file) }",
+		WithVars(map[string]interface{}{"file": nil}),
+		WithFiles(map[string]Upload{
+			"file": {
+				Reader:      strings.NewReader("hello upload"),
+				Filename:    "hello.txt",
+				ContentType: "text/plain",
+			},
+		}),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	var responseData map[string]bool
+	_, gqerr, err := client.RunRaw(ctx, req, &responseData)
+	if err != nil {
+		t.Fatalf("clientRun should not return error: %v", err)
+	}
+	if gqerr != nil {
+		t.Errorf("there should be no graphql errors: %v", gqerr)
+	}
+	if !responseData["upload"] {
+		t.Errorf("expected upload response to be true, got %#v", responseData)
+	}
+}
+
+func TestRunWithMultiFileUpload(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(10 << 20); err != nil {
+			t.Fatalf("ParseMultipartForm: %v", err)
+		}
+
+		gotMap := strings.TrimSpace(r.FormValue("map"))
+		wantMap := `{"0":["variables.files.0"],"1":["variables.files.1"]}`
+		if gotMap != wantMap {
+			t.Errorf("map = %q, want %q", gotMap, wantMap)
+		}
+
+		io.WriteString(w, `{"data": {"upload": true}}`)
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+
+	uploads := []Upload{
+		{Reader: strings.NewReader("one"), Filename: "one.txt"},
+		{Reader: strings.NewReader("two"), Filename: "two.txt"},
+	}
+
+	req := NewRequest(
+		"mutation upload($files: [Upload!]!) { upload(files: $files) }",
+		WithVars(map[string]interface{}{"files": []interface{}{nil, nil}}),
+		WithFiles(MultiUpload("files", uploads)),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	if _, _, err := client.RunRaw(ctx, req, nil); err != nil {
+		t.Fatalf("clientRun should not return error: %v", err)
+	}
+}
+
+// TestRunWithFileUploadDoesNotMutateCallerVars guards against
+// multipartRoundTrip nulling out the upload slot in a nested map the
+// caller's original variables still reference (here an "input" object
+// holding the file slot).
+func TestRunWithFileUploadDoesNotMutateCallerVars(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(10 << 20); err != nil {
+			t.Fatalf("ParseMultipartForm: %v", err)
+		}
+		io.WriteString(w, `{"data": {"upload": true}}`)
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+
+	input := map[string]interface{}{"file": "placeholder"}
+	vars := map[string]interface{}{"input": input}
+
+	req := NewRequest(
+		"mutation upload($input: UploadInput!) { upload(input: $input) }",
+		WithVars(vars),
+		WithFiles(map[string]Upload{
+			"input.file": {Reader: strings.NewReader("hello upload"), Filename: "hello.txt"},
+		}),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	if _, _, err := client.RunRaw(ctx, req, nil); err != nil {
+		t.Fatalf("clientRun should not return error: %v", err)
+	}
+
+	if input["file"] != "placeholder" {
+		t.Errorf("caller's nested input map was mutated: %#v", input)
+	}
+}